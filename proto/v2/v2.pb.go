@@ -0,0 +1,103 @@
+// Code generated by protoc-gen-go.
+// source: v2.proto
+// DO NOT EDIT!
+
+/*
+Package v2 is a generated protocol buffer package.
+
+It is generated from these files:
+	v2.proto
+
+It has these top-level messages:
+	Key
+	SignedKey
+	GetUserRequest
+	GetUserResponse
+	CreateKeyRequest
+	UpdateKeyRequest
+	DeleteKeyRequest
+*/
+package v2
+
+import timestamp "github.com/golang/protobuf/ptypes/timestamp"
+
+// Key is the public key object stored and served by the key server.
+type Key struct {
+	KeyId        string               `protobuf:"bytes,1,opt,name=key_id,json=keyId" json:"key_id,omitempty"`
+	AppId        string               `protobuf:"bytes,2,opt,name=app_id,json=appId" json:"app_id,omitempty"`
+	KeyMaterial  []byte               `protobuf:"bytes,3,opt,name=key_material,json=keyMaterial,proto3" json:"key_material,omitempty"`
+	CreationTime *timestamp.Timestamp `protobuf:"bytes,4,opt,name=creation_time,json=creationTime" json:"creation_time,omitempty"`
+}
+
+func (m *Key) GetCreationTime() *timestamp.Timestamp {
+	if m != nil {
+		return m.CreationTime
+	}
+	return nil
+}
+
+// SignedKey wraps a Key together with the signature that attests to it.
+type SignedKey struct {
+	Key       *Key   `protobuf:"bytes,1,opt,name=key" json:"key,omitempty"`
+	Signature []byte `protobuf:"bytes,2,opt,name=signature,proto3" json:"signature,omitempty"`
+}
+
+func (m *SignedKey) GetKey() *Key {
+	if m != nil {
+		return m.Key
+	}
+	return nil
+}
+
+// GetUserRequest requests the keys published by UserId, optionally scoped to
+// a single AppId and a point in time.
+type GetUserRequest struct {
+	UserId string               `protobuf:"bytes,1,opt,name=user_id,json=userId" json:"user_id,omitempty"`
+	AppId  string               `protobuf:"bytes,2,opt,name=app_id,json=appId" json:"app_id,omitempty"`
+	Time   *timestamp.Timestamp `protobuf:"bytes,3,opt,name=time" json:"time,omitempty"`
+}
+
+func (m *GetUserRequest) GetTime() *timestamp.Timestamp {
+	if m != nil {
+		return m.Time
+	}
+	return nil
+}
+
+// GetUserResponse returns the signed keys known for the requested user.
+type GetUserResponse struct {
+	SignedKeys []*SignedKey `protobuf:"bytes,1,rep,name=signed_keys,json=signedKeys" json:"signed_keys,omitempty"`
+}
+
+// CreateKeyRequest publishes a new key for UserId.
+type CreateKeyRequest struct {
+	UserId    string     `protobuf:"bytes,1,opt,name=user_id,json=userId" json:"user_id,omitempty"`
+	SignedKey *SignedKey `protobuf:"bytes,2,opt,name=signed_key,json=signedKey" json:"signed_key,omitempty"`
+}
+
+func (m *CreateKeyRequest) GetSignedKey() *SignedKey {
+	if m != nil {
+		return m.SignedKey
+	}
+	return nil
+}
+
+// UpdateKeyRequest replaces KeyId belonging to UserId with a new signed key.
+type UpdateKeyRequest struct {
+	UserId    string     `protobuf:"bytes,1,opt,name=user_id,json=userId" json:"user_id,omitempty"`
+	KeyId     string     `protobuf:"bytes,2,opt,name=key_id,json=keyId" json:"key_id,omitempty"`
+	SignedKey *SignedKey `protobuf:"bytes,3,opt,name=signed_key,json=signedKey" json:"signed_key,omitempty"`
+}
+
+func (m *UpdateKeyRequest) GetSignedKey() *SignedKey {
+	if m != nil {
+		return m.SignedKey
+	}
+	return nil
+}
+
+// DeleteKeyRequest revokes KeyId belonging to UserId.
+type DeleteKeyRequest struct {
+	UserId string `protobuf:"bytes,1,opt,name=user_id,json=userId" json:"user_id,omitempty"`
+	KeyId  string `protobuf:"bytes,2,opt,name=key_id,json=keyId" json:"key_id,omitempty"`
+}
@@ -0,0 +1,113 @@
+// Copyright 2015 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rest
+
+import (
+	"bytes"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// deadlineTimer pairs a cancel channel with the timer that closes it,
+// following the net.Conn deadline idiom: Reset to a zero duration disables
+// the timer, and Stopping a timer that has already fired must allocate a
+// fresh cancel channel so a later Reset starts clean.
+type deadlineTimer struct {
+	mu     sync.Mutex
+	timer  *time.Timer
+	cancel chan struct{}
+}
+
+func newDeadlineTimer() *deadlineTimer {
+	return &deadlineTimer{cancel: make(chan struct{})}
+}
+
+// Cancel returns the channel that closes when the deadline fires. The
+// channel returned by a given call stays valid until the next Reset or Stop
+// that needs to allocate a fresh one.
+func (d *deadlineTimer) Cancel() <-chan struct{} {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.cancel
+}
+
+// Reset arms the timer to close Cancel's channel after dur. A dur of zero or
+// less disables the timer entirely.
+func (d *deadlineTimer) Reset(dur time.Duration) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.stopLocked()
+	if dur <= 0 {
+		return
+	}
+	cancel := d.cancel
+	d.timer = time.AfterFunc(dur, func() { close(cancel) })
+}
+
+// Stop disarms the timer without letting it fire.
+func (d *deadlineTimer) Stop() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.stopLocked()
+}
+
+func (d *deadlineTimer) stopLocked() {
+	if d.timer != nil && !d.timer.Stop() {
+		// The timer already fired and closed the old cancel channel; a
+		// fresh one is required before this deadlineTimer can be armed
+		// again.
+		d.cancel = make(chan struct{})
+	}
+	d.timer = nil
+}
+
+// bufferedResponseWriter collects a handler's response in memory instead of
+// writing it straight to the live connection, the way net/http.TimeoutHandler
+// does. AddHandler runs the handler against a bufferedResponseWriter and only
+// copies it to the real http.ResponseWriter if the handler wins its race
+// against the route's deadline; if the deadline fires first, AddHandler
+// answers the request itself and the buffer is discarded. That keeps "the
+// handler wrote a response" and "the dispatcher wrote a timeout" mutually
+// exclusive - at most one of them ever reaches the real ResponseWriter, no
+// matter how closely the handler's write and the deadline firing land.
+type bufferedResponseWriter struct {
+	header     http.Header
+	statusCode int
+	body       bytes.Buffer
+}
+
+func newBufferedResponseWriter() *bufferedResponseWriter {
+	return &bufferedResponseWriter{header: make(http.Header), statusCode: http.StatusOK}
+}
+
+func (b *bufferedResponseWriter) Header() http.Header { return b.header }
+
+func (b *bufferedResponseWriter) Write(p []byte) (int, error) { return b.body.Write(p) }
+
+func (b *bufferedResponseWriter) WriteHeader(statusCode int) { b.statusCode = statusCode }
+
+// flushTo copies the buffered response to w. Callers must only do this once
+// they know no one else has written, or will write, to w on this request's
+// behalf.
+func (b *bufferedResponseWriter) flushTo(w http.ResponseWriter) {
+	for k, vv := range b.header {
+		for _, v := range vv {
+			w.Header().Add(k, v)
+		}
+	}
+	w.WriteHeader(b.statusCode)
+	w.Write(b.body.Bytes())
+}
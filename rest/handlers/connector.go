@@ -0,0 +1,67 @@
+// Copyright 2015 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// bearerToken extracts the token from a "Bearer <token>" Authorization
+// header, as sent by all three built-in connectors.
+func bearerToken(req *http.Request) (string, error) {
+	auth := req.Header.Get("Authorization")
+	const prefix = "Bearer "
+	if !strings.HasPrefix(auth, prefix) {
+		return "", fmt.Errorf("handlers: missing bearer token")
+	}
+	return strings.TrimPrefix(auth, prefix), nil
+}
+
+// connectorConfig is the subset of fields common to every connector's JSON
+// configuration, mirroring dex's connector config envelope, e.g.
+// {"type":"github","id":"github","clientID":"...","clientSecret":"..."}.
+type connectorConfig struct {
+	Type         string `json:"type"`
+	ID           string `json:"id"`
+	ClientID     string `json:"clientID"`
+	ClientSecret string `json:"clientSecret"`
+	RedirectURI  string `json:"redirectURI"`
+	// Issuer is only used by the generic "oidc" connector.
+	Issuer string `json:"issuer"`
+}
+
+// NewConnector parses a single dex-style connector config and returns the
+// IdentityConnector it describes. Unknown "type" values are rejected so a
+// typo in configuration fails at startup rather than silently granting no
+// identity checks.
+func NewConnector(config []byte) (IdentityConnector, error) {
+	var c connectorConfig
+	if err := json.Unmarshal(config, &c); err != nil {
+		return nil, fmt.Errorf("handlers: invalid connector config: %v", err)
+	}
+	switch c.Type {
+	case "github":
+		return newGithubConnector(c), nil
+	case "google":
+		return newGoogleConnector(c), nil
+	case "oidc":
+		return newOIDCConnector(c)
+	default:
+		return nil, fmt.Errorf("handlers: unknown connector type %q", c.Type)
+	}
+}
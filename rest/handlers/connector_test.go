@@ -0,0 +1,106 @@
+// Copyright 2015 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package handlers
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestNewConnector(t *testing.T) {
+	var tests = []struct {
+		desc       string
+		config     string
+		wantType   string
+		wantNilErr bool
+	}{
+		{
+			desc:       "github",
+			config:     `{"type":"github","id":"github","clientID":"id","clientSecret":"secret"}`,
+			wantType:   "github",
+			wantNilErr: true,
+		},
+		{
+			desc:       "google",
+			config:     `{"type":"google","id":"google","clientID":"id","clientSecret":"secret"}`,
+			wantType:   "google",
+			wantNilErr: true,
+		},
+		{
+			desc:       "oidc missing issuer",
+			config:     `{"type":"oidc","id":"oidc","clientID":"id","clientSecret":"secret"}`,
+			wantNilErr: false,
+		},
+		{
+			desc:       "unknown type",
+			config:     `{"type":"facebook","id":"facebook"}`,
+			wantNilErr: false,
+		},
+		{
+			desc:       "malformed JSON",
+			config:     `not json`,
+			wantNilErr: false,
+		},
+	}
+
+	for _, test := range tests {
+		connector, err := NewConnector([]byte(test.config))
+		if got, want := (err == nil), test.wantNilErr; got != want {
+			t.Errorf("%v: NewConnector() err = %v, want nil = %v", test.desc, err, want)
+			continue
+		}
+		if err != nil {
+			continue
+		}
+		if got, want := connector.Type(), test.wantType; got != want {
+			t.Errorf("%v: Type() = %v, want %v", test.desc, got, want)
+		}
+		if state, url := "xyz", connector.LoginURL("xyz"); !strings.Contains(url, state) {
+			t.Errorf("%v: LoginURL(%v) = %v, want it to carry the state", test.desc, state, url)
+		}
+	}
+}
+
+func TestBearerToken(t *testing.T) {
+	var tests = []struct {
+		desc       string
+		authHeader string
+		wantToken  string
+		wantNilErr bool
+	}{
+		{"valid bearer token", "Bearer abc123", "abc123", true},
+		{"missing header", "", "", false},
+		{"wrong scheme", "Basic abc123", "", false},
+	}
+
+	for _, test := range tests {
+		r, _ := http.NewRequest("GET", "/", nil)
+		if test.authHeader != "" {
+			r.Header.Set("Authorization", test.authHeader)
+		}
+		token, err := bearerToken(r)
+		if got, want := (err == nil), test.wantNilErr; got != want {
+			t.Errorf("%v: bearerToken() err = %v, want nil = %v", test.desc, err, want)
+			continue
+		}
+		if err != nil {
+			continue
+		}
+		if got, want := token, test.wantToken; got != want {
+			t.Errorf("%v: bearerToken() = %v, want %v", test.desc, got, want)
+		}
+	}
+}
@@ -0,0 +1,116 @@
+// Copyright 2015 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	context "golang.org/x/net/context"
+	"golang.org/x/oauth2"
+	oagithub "golang.org/x/oauth2/github"
+)
+
+const (
+	githubUserAPI       = "https://api.github.com/user"
+	githubUserEmailsAPI = "https://api.github.com/user/emails"
+)
+
+// githubConnector authenticates bearer tokens against the GitHub user API and
+// asserts the caller's primary email and numeric account id.
+type githubConnector struct {
+	id     string
+	oauth2 *oauth2.Config
+}
+
+func newGithubConnector(c connectorConfig) *githubConnector {
+	return &githubConnector{
+		id: c.ID,
+		oauth2: &oauth2.Config{
+			ClientID:     c.ClientID,
+			ClientSecret: c.ClientSecret,
+			RedirectURL:  c.RedirectURI,
+			Endpoint:     oagithub.Endpoint,
+			Scopes:       []string{"user:email"},
+		},
+	}
+}
+
+func (c *githubConnector) Type() string { return "github" }
+
+func (c *githubConnector) LoginURL(state string) string {
+	return c.oauth2.AuthCodeURL(state)
+}
+
+func (c *githubConnector) Verify(ctx context.Context, req *http.Request) (Identity, error) {
+	token, err := bearerToken(req)
+	if err != nil {
+		return Identity{}, err
+	}
+	client := c.oauth2.Client(ctx, &oauth2.Token{AccessToken: token})
+
+	resp, err := client.Get(githubUserAPI)
+	if err != nil {
+		return Identity{}, fmt.Errorf("github: verifying token: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return Identity{}, fmt.Errorf("github: %v from %v", resp.Status, githubUserAPI)
+	}
+	var user struct {
+		ID int `json:"id"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&user); err != nil {
+		return Identity{}, fmt.Errorf("github: decoding user: %v", err)
+	}
+
+	// /user's own "email" field is only the profile's public email, which is
+	// null unless the account opted to publish one - user:email instead
+	// grants /user/emails, which lists every address GitHub has on file.
+	emailsResp, err := client.Get(githubUserEmailsAPI)
+	if err != nil {
+		return Identity{}, fmt.Errorf("github: fetching emails: %v", err)
+	}
+	defer emailsResp.Body.Close()
+	if emailsResp.StatusCode != http.StatusOK {
+		return Identity{}, fmt.Errorf("github: %v from %v", emailsResp.Status, githubUserEmailsAPI)
+	}
+	var emails []struct {
+		Email    string `json:"email"`
+		Primary  bool   `json:"primary"`
+		Verified bool   `json:"verified"`
+	}
+	if err := json.NewDecoder(emailsResp.Body).Decode(&emails); err != nil {
+		return Identity{}, fmt.Errorf("github: decoding emails: %v", err)
+	}
+	var email string
+	for _, e := range emails {
+		if e.Primary && e.Verified {
+			email = e.Email
+			break
+		}
+	}
+	if email == "" {
+		return Identity{}, fmt.Errorf("github: no verified primary email for user %d", user.ID)
+	}
+
+	return Identity{
+		Email:      email,
+		Subject:    strconv.Itoa(user.ID),
+		ProviderID: c.id,
+	}, nil
+}
@@ -0,0 +1,85 @@
+// Copyright 2015 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	context "golang.org/x/net/context"
+	"golang.org/x/oauth2"
+	oagoogle "golang.org/x/oauth2/google"
+)
+
+const googleUserInfoAPI = "https://www.googleapis.com/oauth2/v3/userinfo"
+
+// googleConnector authenticates bearer tokens against Google's OpenID Connect
+// userinfo endpoint and asserts the caller's verified email and subject.
+type googleConnector struct {
+	id     string
+	oauth2 *oauth2.Config
+}
+
+func newGoogleConnector(c connectorConfig) *googleConnector {
+	return &googleConnector{
+		id: c.ID,
+		oauth2: &oauth2.Config{
+			ClientID:     c.ClientID,
+			ClientSecret: c.ClientSecret,
+			RedirectURL:  c.RedirectURI,
+			Endpoint:     oagoogle.Endpoint,
+			Scopes:       []string{"openid", "email"},
+		},
+	}
+}
+
+func (c *googleConnector) Type() string { return "google" }
+
+func (c *googleConnector) LoginURL(state string) string {
+	return c.oauth2.AuthCodeURL(state)
+}
+
+func (c *googleConnector) Verify(ctx context.Context, req *http.Request) (Identity, error) {
+	token, err := bearerToken(req)
+	if err != nil {
+		return Identity{}, err
+	}
+	client := c.oauth2.Client(ctx, &oauth2.Token{AccessToken: token})
+	resp, err := client.Get(googleUserInfoAPI)
+	if err != nil {
+		return Identity{}, fmt.Errorf("google: verifying token: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return Identity{}, fmt.Errorf("google: %v from %v", resp.Status, googleUserInfoAPI)
+	}
+	var info struct {
+		Sub           string `json:"sub"`
+		Email         string `json:"email"`
+		EmailVerified bool   `json:"email_verified"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&info); err != nil {
+		return Identity{}, fmt.Errorf("google: decoding userinfo: %v", err)
+	}
+	if !info.EmailVerified {
+		return Identity{}, fmt.Errorf("google: email %v is not verified", info.Email)
+	}
+	return Identity{
+		Email:      info.Email,
+		Subject:    info.Sub,
+		ProviderID: c.id,
+	}, nil
+}
@@ -0,0 +1,100 @@
+// Copyright 2015 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package handlers defines the types shared between the REST dispatcher in
+// package rest and the per-API handler functions it invokes.
+package handlers
+
+import (
+	"net/http"
+	"time"
+
+	context "golang.org/x/net/context"
+)
+
+// RequestHandler is implemented by the backend entry point for a single API
+// call, e.g. the gRPC client method that the REST handler proxies to.
+type RequestHandler func(srv interface{}, ctx context.Context, arg interface{}) (*interface{}, error)
+
+// Initializer builds the HandlerInfo used to serve a given route. The
+// dispatcher calls it afresh for every request it serves, and it is free to
+// inspect RouteInfo (for example its UserIdIndex) to decide how to parse
+// incoming requests.
+type Initializer func(rInfo RouteInfo) *HandlerInfo
+
+// RouteInfo describes how a single REST route is dispatched: the path it is
+// registered under, where in that path the user-id and key-id components
+// live (-1 if the route has none), the HTTP method it answers, the
+// Initializer used to build its HandlerInfo, and the RequestHandler that
+// eventually services the call.
+type RouteInfo struct {
+	Path            string
+	UserIdIndex     int
+	KeyIdIndex      int
+	Method          string
+	Initializer     Initializer
+	Handler         RequestHandler
+	RequireIdentity bool
+	// Deadline bounds how long a request to this route may run, identity
+	// check and handler together. When it fires, the request's context is
+	// cancelled; that context carries no deadline value of its own, so
+	// ctx.Deadline reports none. Zero means "use the Server's current
+	// read/write deadlines", which can be changed at runtime with
+	// SetReadDeadline and SetWriteDeadline without re-registering routes.
+	Deadline time.Duration
+}
+
+// HandlerInfo is produced by a RouteInfo's Initializer and carries everything
+// the dispatcher needs to service one request: the zero value of the
+// argument proto to decode into, the Parser that fills it in from the
+// http.Request, and the RequestHandler to invoke once it's populated.
+type HandlerInfo struct {
+	Arg    interface{}
+	Parser func(r *http.Request, arg interface{}) error
+	H      RequestHandler
+	// Deadline, when positive, overrides RouteInfo.Deadline for this single
+	// request - an Initializer can set it from the RouteInfo it is given. The
+	// overriding deadline is counted from when the Initializer returns.
+	Deadline time.Duration
+}
+
+// HandlerFunc is the shape of the function registered with
+// rest.Server.AddHandler. It is handed the live HandlerInfo for the matched
+// route so it can run the Parser and invoke H. Identity, where the route
+// requires it, has already been checked before it is called.
+type HandlerFunc func(srv interface{}, ctx context.Context, w http.ResponseWriter, r *http.Request, info *HandlerInfo) error
+
+// Identity is the verified caller identity produced by an IdentityConnector.
+// Email and Subject are as asserted by the identity provider; ProviderID
+// names the connector that produced the assertion by its configured "id".
+type Identity struct {
+	Email      string
+	Subject    string
+	ProviderID string
+}
+
+// IdentityConnector verifies the bearer of an incoming request and returns
+// the Identity it authenticates as. Implementations wrap a single OAuth2/OIDC
+// provider, configured the way dex configures its connectors: a JSON blob
+// carrying at least a "type" and "id" field.
+type IdentityConnector interface {
+	// Type returns the connector kind, e.g. "github", "google", "oidc".
+	Type() string
+	// LoginURL returns the provider's authorization endpoint URL that starts
+	// the login flow, round-tripping state through the redirect.
+	LoginURL(state string) string
+	// Verify authenticates the credentials carried by req (typically a
+	// bearer token) and returns the Identity they assert.
+	Verify(ctx context.Context, req *http.Request) (Identity, error)
+}
@@ -0,0 +1,423 @@
+// Copyright 2015 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package rest implements the JSON/HTTP front end that proxies to the key
+// server's gRPC API. Each API call is described by a handlers.RouteInfo that
+// is registered with a Server via AddHandler; the Server parses the request
+// into the proto the backend expects, optionally checks caller identity, and
+// invokes the backend RequestHandler.
+package rest
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/e2e-key-server/rest/handlers"
+
+	v2pb "github.com/google/e2e-key-server/proto/v2"
+	timestamp "github.com/golang/protobuf/ptypes/timestamp"
+	context "golang.org/x/net/context"
+)
+
+// Server dispatches REST requests to a backend (typically the in-process
+// implementation of the v2 gRPC API) according to the routes registered with
+// AddHandler.
+type Server struct {
+	svr        interface{}
+	mux        *http.ServeMux
+	connectors []handlers.IdentityConnector
+
+	deadlineMu    sync.RWMutex
+	readDeadline  time.Duration
+	writeDeadline time.Duration
+}
+
+// New creates a Server that proxies requests to svr.
+func New(svr interface{}) *Server {
+	return &Server{
+		svr: svr,
+		mux: http.NewServeMux(),
+	}
+}
+
+// SetReadDeadline sets the read share of the default total request budget
+// used by routes with no RouteInfo.Deadline of their own. Only the sum of the
+// read and write deadlines is enforced, as one budget for the whole request;
+// parsing is not timed on its own. It takes effect for requests dispatched
+// after the call returns, without requiring routes to be re-registered.
+func (s *Server) SetReadDeadline(d time.Duration) {
+	s.deadlineMu.Lock()
+	defer s.deadlineMu.Unlock()
+	s.readDeadline = d
+}
+
+// SetWriteDeadline sets the write share of the default total request budget
+// used by routes with no RouteInfo.Deadline of their own. It combines with
+// SetReadDeadline into that budget; producing and writing the response is
+// not timed on its own.
+func (s *Server) SetWriteDeadline(d time.Duration) {
+	s.deadlineMu.Lock()
+	defer s.deadlineMu.Unlock()
+	s.writeDeadline = d
+}
+
+// defaultDeadline returns the Server's current total request budget - the
+// sum of its read and write deadlines - used for any route that doesn't set
+// its own RouteInfo.Deadline.
+func (s *Server) defaultDeadline() time.Duration {
+	s.deadlineMu.RLock()
+	defer s.deadlineMu.RUnlock()
+	return s.readDeadline + s.writeDeadline
+}
+
+// WithIdentity configures the IdentityConnectors a Server consults when a
+// route's RouteInfo.RequireIdentity is set. It returns the Server so it can
+// be chained with New.
+func (s *Server) WithIdentity(connectors ...handlers.IdentityConnector) *Server {
+	s.connectors = connectors
+	return s
+}
+
+// Handlers returns the http.Handler serving every route registered so far.
+func (s *Server) Handlers() http.Handler {
+	return s.mux
+}
+
+// AddHandler registers rInfo's path and method against the Server, running
+// handleFunc once the request's method matches. The whole request - identity
+// check and handleFunc alike - runs under a context that expires after
+// rInfo.Deadline (or the Server's default, see SetReadDeadline), so neither a
+// slow identity provider nor a slow backend call can hold the connection open
+// past it; whichever is still running when the deadline fires loses the race
+// and a 504 is written for it instead. If rInfo.RequireIdentity is set, the
+// caller must present credentials that verify, via one of the Server's
+// configured connectors, to the same email or subject as the user-id path
+// component at rInfo.UserIdIndex; unverified or mismatched callers never
+// reach handleFunc.
+func (s *Server) AddHandler(rInfo handlers.RouteInfo, handleFunc handlers.HandlerFunc) {
+	s.mux.HandleFunc(rInfo.Path, func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != rInfo.Method {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		deadline := rInfo.Deadline
+		if deadline <= 0 {
+			deadline = s.defaultDeadline()
+		}
+
+		dt := newDeadlineTimer()
+		dt.Reset(deadline)
+		defer dt.Stop()
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		go func() {
+			select {
+			case <-dt.Cancel():
+				cancel()
+			case <-ctx.Done():
+			}
+		}()
+
+		// authorize runs under the same deadline as handleFunc below, so a
+		// slow or unresponsive identity provider (a network round trip to
+		// GitHub, Google or an OIDC issuer) can't hang the request any
+		// longer than a slow backend call could.
+		if rInfo.RequireIdentity {
+			if err := s.authorize(ctx, r, rInfo); err != nil {
+				status := statusForAuthError(err)
+				if ctx.Err() != nil {
+					status = http.StatusGatewayTimeout
+				}
+				http.Error(w, err.Error(), status)
+				return
+			}
+		}
+
+		info := rInfo.Initializer(rInfo)
+		if info != nil && info.Deadline > 0 && info.Deadline != deadline {
+			dt.Reset(info.Deadline)
+		}
+
+		// bw buffers handleFunc's response instead of writing it straight to
+		// the live connection, so the write below and whatever handleFunc
+		// does in its own goroutine can never land on w at the same time:
+		// whichever case of this select runs is the only one that ever
+		// touches w, and the other's output - buffered or not yet produced -
+		// is simply discarded.
+		bw := newBufferedResponseWriter()
+		done := make(chan error, 1)
+		go func() {
+			done <- handleFunc(s.svr, ctx, bw, r, info)
+		}()
+
+		select {
+		case err := <-done:
+			if err != nil {
+				// ctx.Err() is set as soon as the deadline fires (see the
+				// bridging goroutine above), regardless of whether this
+				// select or the handler itself noticed first, so it - not
+				// which case of this select happened to run - decides
+				// whether this was a timeout.
+				status := http.StatusInternalServerError
+				if ctx.Err() != nil {
+					status = http.StatusGatewayTimeout
+				}
+				http.Error(w, err.Error(), status)
+				return
+			}
+			bw.flushTo(w)
+		case <-dt.Cancel():
+			http.Error(w, "deadline exceeded", http.StatusGatewayTimeout)
+		}
+	})
+}
+
+// authError records why authorize rejected a request, so AddHandler can pick
+// the right status code without string-matching error text.
+type authError struct {
+	status int
+	err    error
+}
+
+func (e *authError) Error() string { return e.err.Error() }
+
+func statusForAuthError(err error) int {
+	if ae, ok := err.(*authError); ok {
+		return ae.status
+	}
+	return http.StatusInternalServerError
+}
+
+// authorize verifies the caller against every configured connector and
+// requires the resulting Identity's email or subject to match the user-id
+// path component rInfo.UserIdIndex names, so e2eshare.test@gmail.com's keys
+// can only be mutated by a token that itself verifies as
+// e2eshare.test@gmail.com. A connector whose Verify succeeds but whose
+// identity doesn't match doesn't end the search - with more than one
+// connector configured, a token that happens to verify against the wrong
+// provider shouldn't mask a different connector that would have matched -
+// so every connector is tried before a mismatch is reported.
+func (s *Server) authorize(ctx context.Context, r *http.Request, rInfo handlers.RouteInfo) error {
+	userID, err := parseURLComponent(splitPath(r.URL.Path), rInfo.UserIdIndex)
+	if err != nil {
+		return &authError{http.StatusBadRequest, fmt.Errorf("rest: cannot determine user id: %v", err)}
+	}
+
+	if len(s.connectors) == 0 {
+		return &authError{http.StatusUnauthorized, fmt.Errorf("rest: no identity connectors configured")}
+	}
+
+	var lastErr, mismatchErr error
+	for _, connector := range s.connectors {
+		identity, err := connector.Verify(ctx, r)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		if identity.Email == userID || identity.Subject == userID {
+			return nil
+		}
+		mismatchErr = fmt.Errorf("rest: identity %v does not match user id %v", identity.Email, userID)
+	}
+	if mismatchErr != nil {
+		return &authError{http.StatusForbidden, mismatchErr}
+	}
+	return &authError{http.StatusUnauthorized, fmt.Errorf("rest: no connector verified the request: %v", lastErr)}
+}
+
+// splitPath turns a URL path like "/v1/users/foo@bar/keys" into
+// ["v1", "users", "foo@bar", "keys"], matching the indices RouteInfo.UserIdIndex
+// and RouteInfo.KeyIdIndex are defined against.
+func splitPath(path string) []string {
+	return strings.Split(strings.TrimPrefix(path, "/"), "/")
+}
+
+// parseURLComponent returns components[index], or an error if index doesn't
+// name a valid component.
+func parseURLComponent(components []string, index int) (string, error) {
+	if index < 0 || index >= len(components) {
+		return "", fmt.Errorf("rest: no URL component at index %d", index)
+	}
+	return components[index], nil
+}
+
+// parseJSON rewrites every well-formed `"<keyword>": "<RFC3339 timestamp>"`
+// (the keyword may or may not be quoted) occurrence in r.Body into
+// `"<keyword>": {"seconds": ..., "nanos": ...}`, so the body can then be
+// decoded straight into a proto carrying a google.protobuf.Timestamp. It
+// leaves malformed quoting alone (there is nothing sensible to rewrite), but
+// returns an error - and leaves the body untouched - if a properly quoted
+// value fails to parse as RFC3339.
+func parseJSON(r *http.Request, keyword string) error {
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		return err
+	}
+	r.Body.Close()
+
+	out, err := rewriteTimestamps(string(body), keyword)
+	if err != nil {
+		r.Body = ioutil.NopCloser(bytes.NewReader(body))
+		return err
+	}
+	r.Body = ioutil.NopCloser(bytes.NewBufferString(out))
+	return nil
+}
+
+func rewriteTimestamps(body, keyword string) (string, error) {
+	prefix := regexp.MustCompile(`(?:"` + regexp.QuoteMeta(keyword) + `"|` + regexp.QuoteMeta(keyword) + `)\s*:\s*"`)
+
+	var out bytes.Buffer
+	rest := body
+	for {
+		loc := prefix.FindStringIndex(rest)
+		if loc == nil {
+			out.WriteString(rest)
+			return out.String(), nil
+		}
+
+		tail := rest[loc[1]:]
+		end := strings.IndexByte(tail, '"')
+		if end < 0 {
+			// No closing quote anywhere in the remainder: nothing more can
+			// match after this point either.
+			out.WriteString(rest)
+			return out.String(), nil
+		}
+
+		value := tail[:end]
+		ts, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			return body, fmt.Errorf("rest: invalid %s %q: %v", keyword, value, err)
+		}
+
+		out.WriteString(rest[:loc[1]-1])
+		fmt.Fprintf(&out, `{"seconds": %d, "nanos": %d}`, ts.Unix(), ts.Nanosecond())
+		rest = tail[end+1:]
+	}
+}
+
+func newTimestamp(t time.Time) *timestamp.Timestamp {
+	return &timestamp.Timestamp{Seconds: t.Unix(), Nanos: int32(t.Nanosecond())}
+}
+
+// GetUser_InitializeHandlerInfo builds the HandlerInfo for GET
+// /v1/users/{userid}, parsing the user id out of the URL and the optional
+// app_id and time query parameters.
+func GetUser_InitializeHandlerInfo(rInfo handlers.RouteInfo) *handlers.HandlerInfo {
+	info := new(handlers.HandlerInfo)
+	info.Arg = &v2pb.GetUserRequest{}
+	info.H = rInfo.Handler
+	info.Parser = func(r *http.Request, arg interface{}) error {
+		req := arg.(*interface{})
+		out := (*req).(*v2pb.GetUserRequest)
+
+		userID, err := parseURLComponent(splitPath(r.URL.Path), rInfo.UserIdIndex)
+		if err != nil {
+			return err
+		}
+		out.UserId = userID
+		out.AppId = r.URL.Query().Get("app_id")
+
+		if ts := r.URL.Query().Get("time"); ts != "" {
+			t, err := time.Parse(time.RFC3339, ts)
+			if err != nil {
+				return err
+			}
+			out.Time = newTimestamp(t)
+		}
+		return nil
+	}
+	return info
+}
+
+// CreateKey_InitializeHandlerInfo builds the HandlerInfo for POST
+// /v1/users/{userid}/keys.
+func CreateKey_InitializeHandlerInfo(rInfo handlers.RouteInfo) *handlers.HandlerInfo {
+	info := new(handlers.HandlerInfo)
+	info.Arg = &v2pb.CreateKeyRequest{}
+	info.H = rInfo.Handler
+	info.Parser = func(r *http.Request, arg interface{}) error {
+		req := arg.(*interface{})
+		out := (*req).(*v2pb.CreateKeyRequest)
+
+		userID, err := parseURLComponent(splitPath(r.URL.Path), rInfo.UserIdIndex)
+		if err != nil {
+			return err
+		}
+		out.UserId = userID
+		return parseJSON(r, "creation_time")
+	}
+	return info
+}
+
+// UpdateKey_InitializeHandlerInfo builds the HandlerInfo for PUT
+// /v1/users/{userid}/keys/{keyid}.
+func UpdateKey_InitializeHandlerInfo(rInfo handlers.RouteInfo) *handlers.HandlerInfo {
+	info := new(handlers.HandlerInfo)
+	info.Arg = &v2pb.UpdateKeyRequest{}
+	info.H = rInfo.Handler
+	info.Parser = func(r *http.Request, arg interface{}) error {
+		req := arg.(*interface{})
+		out := (*req).(*v2pb.UpdateKeyRequest)
+
+		comps := splitPath(r.URL.Path)
+		userID, err := parseURLComponent(comps, rInfo.UserIdIndex)
+		if err != nil {
+			return err
+		}
+		keyID, err := parseURLComponent(comps, rInfo.KeyIdIndex)
+		if err != nil {
+			return err
+		}
+		out.UserId = userID
+		out.KeyId = keyID
+		return parseJSON(r, "creation_time")
+	}
+	return info
+}
+
+// DeleteKey_InitializeHandlerInfo builds the HandlerInfo for DELETE
+// /v1/users/{userid}/keys/{keyid}.
+func DeleteKey_InitializeHandlerInfo(rInfo handlers.RouteInfo) *handlers.HandlerInfo {
+	info := new(handlers.HandlerInfo)
+	info.Arg = &v2pb.DeleteKeyRequest{}
+	info.H = rInfo.Handler
+	info.Parser = func(r *http.Request, arg interface{}) error {
+		req := arg.(*interface{})
+		out := (*req).(*v2pb.DeleteKeyRequest)
+
+		comps := splitPath(r.URL.Path)
+		userID, err := parseURLComponent(comps, rInfo.UserIdIndex)
+		if err != nil {
+			return err
+		}
+		keyID, err := parseURLComponent(comps, rInfo.KeyIdIndex)
+		if err != nil {
+			return err
+		}
+		out.UserId = userID
+		out.KeyId = keyID
+		return nil
+	}
+	return info
+}
@@ -66,6 +66,20 @@ func Fake_RequestHandler(srv interface{}, ctx context.Context, arg interface{})
 	return i, nil
 }
 
+// fakeConnector is an handlers.IdentityConnector whose Verify outcome is
+// fixed at construction time, for exercising Server.authorize without a real
+// OAuth2/OIDC round trip.
+type fakeConnector struct {
+	identity handlers.Identity
+	err      error
+}
+
+func (c *fakeConnector) Type() string                  { return "fake" }
+func (c *fakeConnector) LoginURL(state string) string  { return "https://fake.example.com/login?state=" + state }
+func (c *fakeConnector) Verify(ctx context.Context, r *http.Request) (handlers.Identity, error) {
+	return c.identity, c.err
+}
+
 func TestFoo(t *testing.T) {
 	v1 := &FakeServer{}
 	s := New(v1)
@@ -76,6 +90,8 @@ func TestFoo(t *testing.T) {
 		"GET",
 		Fake_Initializer,
 		Fake_RequestHandler,
+		false,
+		0,
 	}
 	s.AddHandler(rInfo, Fake_Handler)
 
@@ -90,6 +106,163 @@ func TestFoo(t *testing.T) {
 	}
 }
 
+// Fake_SlowRequestHandler simulates a backend call (a Merkle proof fetch, a
+// signer round-trip) that takes longer than the route's deadline, and aborts
+// as soon as ctx is done rather than running to completion.
+func Fake_SlowRequestHandler(srv interface{}, ctx context.Context, arg interface{}) (*interface{}, error) {
+	select {
+	case <-time.After(time.Second):
+		b := true
+		i := new(interface{})
+		*i = b
+		return i, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+func Fake_SlowInitializer(rInfo handlers.RouteInfo) *handlers.HandlerInfo {
+	return &handlers.HandlerInfo{H: rInfo.Handler}
+}
+
+func Fake_SlowHandler(srv interface{}, ctx context.Context, w http.ResponseWriter, r *http.Request, info *handlers.HandlerInfo) error {
+	_, err := info.H(srv, ctx, info.Arg)
+	return err
+}
+
+func TestDeadlineExceeded(t *testing.T) {
+	rInfo := handlers.RouteInfo{
+		"/slow",
+		-1,
+		-1,
+		"GET",
+		Fake_SlowInitializer,
+		Fake_SlowRequestHandler,
+		false,
+		20 * time.Millisecond,
+	}
+
+	v1 := &FakeServer{}
+	s := New(v1)
+	s.AddHandler(rInfo, Fake_SlowHandler)
+
+	server := httptest.NewServer(s.Handlers())
+	defer server.Close()
+
+	res, err := http.Get(fmt.Sprintf("%s/slow", server.URL))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := res.StatusCode, http.StatusGatewayTimeout; got != want {
+		t.Errorf("GET: %v = %v, want %v", res.Request.URL, got, want)
+	}
+}
+
+func TestSetDeadline_TakesEffectWithoutReregistering(t *testing.T) {
+	rInfo := handlers.RouteInfo{
+		"/slow",
+		-1,
+		-1,
+		"GET",
+		Fake_SlowInitializer,
+		Fake_SlowRequestHandler,
+		false,
+		0,
+	}
+
+	v1 := &FakeServer{}
+	s := New(v1)
+	s.AddHandler(rInfo, Fake_SlowHandler)
+
+	server := httptest.NewServer(s.Handlers())
+	defer server.Close()
+
+	// SetReadDeadline/SetWriteDeadline are called after AddHandler, on an
+	// already-registered route with no Deadline of its own, so the only way
+	// this request can time out is if they took effect at request time.
+	s.SetReadDeadline(10 * time.Millisecond)
+	s.SetWriteDeadline(10 * time.Millisecond)
+
+	res, err := http.Get(fmt.Sprintf("%s/slow", server.URL))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := res.StatusCode, http.StatusGatewayTimeout; got != want {
+		t.Errorf("GET: %v = %v, want %v", res.Request.URL, got, want)
+	}
+}
+
+func TestRequireIdentity(t *testing.T) {
+	path := "/v1/users/" + primary_test_email + "/keys"
+	rInfo := handlers.RouteInfo{
+		path,
+		2,
+		-1,
+		"POST",
+		Fake_Initializer,
+		Fake_RequestHandler,
+		true,
+		0,
+	}
+
+	var tests = []struct {
+		desc       string
+		connectors []handlers.IdentityConnector
+		wantStatus int
+	}{
+		{
+			desc:       "no connectors configured",
+			connectors: nil,
+			wantStatus: http.StatusUnauthorized,
+		},
+		{
+			desc: "connector fails to verify",
+			connectors: []handlers.IdentityConnector{
+				&fakeConnector{err: fmt.Errorf("invalid token")},
+			},
+			wantStatus: http.StatusUnauthorized,
+		},
+		{
+			desc: "identity does not match url user id",
+			connectors: []handlers.IdentityConnector{
+				&fakeConnector{identity: handlers.Identity{Email: "someone.else@gmail.com"}},
+			},
+			wantStatus: http.StatusForbidden,
+		},
+		{
+			desc: "identity matches url user id",
+			connectors: []handlers.IdentityConnector{
+				&fakeConnector{identity: handlers.Identity{Email: primary_test_email}},
+			},
+			wantStatus: http.StatusOK,
+		},
+		{
+			desc: "first connector verifies but does not match, second connector matches",
+			connectors: []handlers.IdentityConnector{
+				&fakeConnector{identity: handlers.Identity{Email: "someone.else@gmail.com"}},
+				&fakeConnector{identity: handlers.Identity{Email: primary_test_email}},
+			},
+			wantStatus: http.StatusOK,
+		},
+	}
+
+	for _, test := range tests {
+		v1 := &FakeServer{}
+		s := New(v1).WithIdentity(test.connectors...)
+		s.AddHandler(rInfo, Fake_Handler)
+
+		server := httptest.NewServer(s.Handlers())
+		res, err := http.Post(server.URL+path, "application/json", bytes.NewBufferString("{}"))
+		if err != nil {
+			t.Fatalf("%v: %v", test.desc, err)
+		}
+		if got, want := res.StatusCode, test.wantStatus; got != want {
+			t.Errorf("%v: POST %v = %v, want %v", test.desc, path, got, want)
+		}
+		server.Close()
+	}
+}
+
 func TestGetUser_InitiateHandlerInfo(t *testing.T) {
 	var tests = []struct {
 		path         string
@@ -121,6 +294,8 @@ func TestGetUser_InitiateHandlerInfo(t *testing.T) {
 			"GET",
 			Fake_Initializer,
 			Fake_RequestHandler,
+			false,
+			0,
 		}
 		// Body is empty when invoking get user API.
 		jsonBody := "{}"
@@ -218,6 +393,8 @@ func TestCreateKey_InitiateHandlerInfo(t *testing.T) {
 			"POST",
 			Fake_Initializer,
 			Fake_RequestHandler,
+			false,
+			0,
 		}
 
 		info := CreateKey_InitializeHandlerInfo(rInfo)
@@ -309,6 +486,8 @@ func TestUpdateKey_InitiateHandlerInfo(t *testing.T) {
 			"PUT",
 			Fake_Initializer,
 			Fake_RequestHandler,
+			false,
+			0,
 		}
 
 		info := UpdateKey_InitializeHandlerInfo(rInfo)
@@ -390,6 +569,8 @@ func TestDeleteKey_InitiateHandlerInfo(t *testing.T) {
 			"DELETE",
 			Fake_Initializer,
 			Fake_RequestHandler,
+			false,
+			0,
 		}
 		// Body is empty when invoking delete key API.
 		jsonBody := "{}"